@@ -0,0 +1,385 @@
+/*
+ * file2qr - Convert files to QR codes
+ * Copyright (C) 2025 file2qr contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	qr "github.com/piglig/go-qr"
+	"github.com/skip2/go-qrcode"
+)
+
+// maxChunks is the largest number of parts Structured Append can address:
+// the total-1 field is 4 bits wide, so a symbol set tops out at 16 members.
+const maxChunks = 16
+
+// structuredAppendModeIndicator is the QR mode indicator (ISO/IEC 18004)
+// that marks a symbol as part of a Structured Append sequence.
+const structuredAppendModeIndicator = 0x3 // 0b0011
+
+// structuredAppendMagic prefixes every Structured Append header so a
+// decoder can tell a chunk apart from an ordinary, non-chunked payload.
+// Without it, a plain payload whose first byte happens to fall in the
+// mode indicator's range would be misparsed as a chunk header.
+var structuredAppendMagic = [4]byte{'F', '2', 'Q', 'C'}
+
+// structuredAppendHeaderLen is the size, in bytes, of structuredAppendMagic
+// plus the packed mode/index/total/parity fields.
+const structuredAppendHeaderLen = len(structuredAppendMagic) + 3
+
+// chunkCapacity holds the usable payload capacity of one chunk for each
+// recovery level: the maximum byte-mode capacity of a version 40 symbol,
+// minus the bytes spent on the Structured Append header. A full chunk plus
+// its header fills a version 40 symbol exactly, with no margin left over.
+var chunkCapacity = map[qrcode.RecoveryLevel]int{
+	qrcode.Low:     2953 - structuredAppendHeaderLen,
+	qrcode.Medium:  2331 - structuredAppendHeaderLen,
+	qrcode.High:    1663 - structuredAppendHeaderLen,
+	qrcode.Highest: 1273 - structuredAppendHeaderLen,
+}
+
+// qrChunk is one Structured Append member: its header and the slice of the
+// original payload it carries.
+type qrChunk struct {
+	index   int // 0-based position in the sequence
+	total   int // number of chunks in the sequence
+	parity  byte
+	payload []byte
+}
+
+// needsChunking reports whether content is too large to fit in a single QR
+// code at the given recovery level.
+func needsChunking(content []byte, recLevel qrcode.RecoveryLevel) bool {
+	return len(content) > chunkCapacity[recLevel]+structuredAppendHeaderLen
+}
+
+// splitIntoChunks divides content into at most maxChunks Structured Append
+// members sized to fit recLevel's capacity. The XOR parity byte is computed
+// once over the whole payload so every chunk carries the same value,
+// letting a decoder confirm chunks belong to the same original file.
+func splitIntoChunks(content []byte, recLevel qrcode.RecoveryLevel) ([]qrChunk, error) {
+	capacity := chunkCapacity[recLevel]
+	if capacity <= 0 {
+		return nil, fmt.Errorf("no usable capacity for recovery level %v", recLevel)
+	}
+
+	total := (len(content) + capacity - 1) / capacity
+	if total <= 1 {
+		total = 1
+	}
+	if total > maxChunks {
+		return nil, fmt.Errorf("content requires %d chunks, which exceeds the Structured Append limit of %d", total, maxChunks)
+	}
+
+	var parity byte
+	for _, b := range content {
+		parity ^= b
+	}
+
+	chunks := make([]qrChunk, 0, total)
+	for i := 0; i < total; i++ {
+		start := i * capacity
+		end := start + capacity
+		if end > len(content) {
+			end = len(content)
+		}
+		chunks = append(chunks, qrChunk{
+			index:   i,
+			total:   total,
+			parity:  parity,
+			payload: content[start:end],
+		})
+	}
+	return chunks, nil
+}
+
+// structuredAppendHeader packs structuredAppendMagic followed by the 4-bit
+// mode indicator, 4-bit chunk index, 4-bit "total-1" and 8-bit parity,
+// padded with 4 zero bits to stay byte-aligned:
+//
+//	bytes[0:4]: structuredAppendMagic
+//	byte[4]:    mode(4) | index(4)
+//	byte[5]:    total-1(4) | parity-high-nibble(4)
+//	byte[6]:    parity-low-nibble(4) | pad(4)
+func structuredAppendHeader(c qrChunk) [structuredAppendHeaderLen]byte {
+	var header [structuredAppendHeaderLen]byte
+	copy(header[:4], structuredAppendMagic[:])
+	header[4] = byte(structuredAppendModeIndicator<<4) | byte(c.index&0xF)
+	header[5] = byte((c.total-1)&0xF)<<4 | (c.parity>>4)&0xF
+	header[6] = (c.parity&0xF)<<4
+	return header
+}
+
+// chunkQRCode builds the low-level QR symbol for a single Structured
+// Append member. The header bytes and the chunk's payload are encoded
+// together as one ordinary byte-mode segment via go-qr's segment API, so
+// the header follows that segment's mode/length framing. No Structured
+// Append segment is written into the symbol's bitstream itself: a generic
+// QR reader sees the header as the first bytes of the data.
+//
+// version is forced (minVer == maxVer == version, boostEcl disabled) rather
+// than left to go-qr's own auto-selection: ISO/IEC 18004 requires every
+// symbol in a Structured Append sequence to share the same version and
+// error correction level, so the caller picks one version up front via
+// chunkSequenceVersion and applies it to every member, including the
+// possibly shorter final chunk.
+func chunkQRCode(c qrChunk, recLevel qrcode.RecoveryLevel, version int) (*qr.QrCode, error) {
+	header := structuredAppendHeader(c)
+	data := make([]byte, 0, len(header)+len(c.payload))
+	data = append(data, header[:]...)
+	data = append(data, c.payload...)
+
+	seg, err := qr.MakeBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("building chunk %d/%d segment: %w", c.index+1, c.total, err)
+	}
+	qrc, err := qr.EncodeSegments([]*qr.QrSegment{seg}, toGoQrEcc(recLevel), version, version, -1, false)
+	if err != nil {
+		return nil, fmt.Errorf("encoding chunk %d/%d: %w", c.index+1, c.total, err)
+	}
+	return qrc, nil
+}
+
+// chunkSequenceVersion picks the single QR version every member of chunks
+// must be encoded at. It is derived from the largest chunk — the one most
+// likely to need extra capacity — by letting go-qr auto-select a version
+// for it, then reading that version back out of the resulting symbol's
+// side length (side = 4*version + 17, per ISO/IEC 18004). Every chunk,
+// including a shorter final one, is then forced onto that same version by
+// chunkQRCode so the whole sequence stays uniform.
+func chunkSequenceVersion(chunks []qrChunk, recLevel qrcode.RecoveryLevel) (int, error) {
+	largest := chunks[0]
+	for _, c := range chunks[1:] {
+		if len(c.payload) > len(largest.payload) {
+			largest = c
+		}
+	}
+
+	header := structuredAppendHeader(largest)
+	data := make([]byte, 0, len(header)+len(largest.payload))
+	data = append(data, header[:]...)
+	data = append(data, largest.payload...)
+
+	seg, err := qr.MakeBytes(data)
+	if err != nil {
+		return 0, fmt.Errorf("building chunk %d/%d segment: %w", largest.index+1, largest.total, err)
+	}
+	qrc, err := qr.EncodeSegments([]*qr.QrSegment{seg}, toGoQrEcc(recLevel), qr.MinVersion, qr.MaxVersion, -1, false)
+	if err != nil {
+		return 0, fmt.Errorf("sizing chunk sequence: %w", err)
+	}
+	return (qrc.Size() - 17) / 4, nil
+}
+
+// chunkModuleBorder is the quiet zone, in modules, go-qr draws around a
+// chunk image, matching the 4-module border the QR spec recommends.
+const chunkModuleBorder = 4
+
+// chunkModuleScale converts the requested total image size in pixels (the
+// same -s/--size the single-QR path uses) into go-qr's per-module pixel
+// scale, so a chunked PNG/GIF/grid comes out close to the requested size
+// regardless of how many modules the symbol ends up with.
+func chunkModuleScale(qrc *qr.QrCode, size int) int {
+	modules := qrc.Size() + 2*chunkModuleBorder
+	scale := size / modules
+	if scale < 1 {
+		scale = 1
+	}
+	return scale
+}
+
+// encodeChunk renders a single Structured Append member to an image sized
+// to approximate size pixels, suitable for writing to a PNG/GIF/grid file.
+func encodeChunk(c qrChunk, recLevel qrcode.RecoveryLevel, version, size int) (image.Image, error) {
+	qrc, err := chunkQRCode(c, recLevel, version)
+	if err != nil {
+		return nil, err
+	}
+	cfg := qr.NewQrCodeImgConfig(chunkModuleScale(qrc, size), chunkModuleBorder)
+	return qrc.ToImage(cfg)
+}
+
+// chunkBitmap renders a single Structured Append member to its module
+// grid, for TerminalRenderer to draw directly.
+func chunkBitmap(c qrChunk, recLevel qrcode.RecoveryLevel, version int) ([][]bool, error) {
+	qrc, err := chunkQRCode(c, recLevel, version)
+	if err != nil {
+		return nil, err
+	}
+	size := qrc.Size()
+	bitmap := make([][]bool, size)
+	for y := 0; y < size; y++ {
+		bitmap[y] = make([]bool, size)
+		for x := 0; x < size; x++ {
+			bitmap[y][x] = qrc.Module(x, y)
+		}
+	}
+	return bitmap, nil
+}
+
+// toGoQrEcc maps this tool's recovery levels onto go-qr's error correction
+// constants so the chunked path behaves the same as the single-QR path.
+func toGoQrEcc(recLevel qrcode.RecoveryLevel) qr.Ecc {
+	switch recLevel {
+	case qrcode.Low:
+		return qr.Low
+	case qrcode.Medium:
+		return qr.Medium
+	case qrcode.High:
+		return qr.Quartile
+	case qrcode.Highest:
+		return qr.High
+	default:
+		return qr.Medium
+	}
+}
+
+// parsedChunk is the result of reading a Structured Append header back out
+// of a scanned QR payload.
+type parsedChunk struct {
+	index   int
+	total   int
+	parity  byte
+	payload []byte
+}
+
+// parseStructuredAppendHeader reverses structuredAppendHeader: given the
+// raw bytes decoded from a QR symbol, it reports whether they begin with
+// structuredAppendMagic and, if so, splits off the header from the
+// chunk's share of the payload. The magic check (rather than just the
+// mode nibble) is what lets an ordinary, non-chunked payload pass through
+// undetected instead of colliding with the header format.
+func parseStructuredAppendHeader(data []byte) (parsedChunk, bool) {
+	if len(data) < structuredAppendHeaderLen {
+		return parsedChunk{}, false
+	}
+	if !bytes.Equal(data[:4], structuredAppendMagic[:]) {
+		return parsedChunk{}, false
+	}
+	mode := data[4] >> 4
+	if mode != structuredAppendModeIndicator {
+		return parsedChunk{}, false
+	}
+
+	index := int(data[4] & 0xF)
+	total := int(data[5]>>4) + 1
+	parity := (data[5]&0xF)<<4 | (data[6] >> 4)
+
+	return parsedChunk{
+		index:   index,
+		total:   total,
+		parity:  parity,
+		payload: data[structuredAppendHeaderLen:],
+	}, true
+}
+
+// reassembleChunks orders Structured Append members by index, verifies
+// every chunk shares the same total count and XOR parity byte, and
+// concatenates their payloads back into the original content.
+func reassembleChunks(chunks []parsedChunk) ([]byte, error) {
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no chunks to reassemble")
+	}
+
+	total := chunks[0].total
+	parity := chunks[0].parity
+	ordered := make([][]byte, total)
+	seen := make([]bool, total)
+
+	for _, c := range chunks {
+		if c.total != total || c.parity != parity {
+			return nil, fmt.Errorf("chunk %d/%d does not belong to this sequence (parity mismatch)", c.index+1, c.total)
+		}
+		if c.index < 0 || c.index >= total {
+			return nil, fmt.Errorf("chunk index %d out of range for %d-chunk sequence", c.index, total)
+		}
+		ordered[c.index] = c.payload
+		seen[c.index] = true
+	}
+
+	for i, ok := range seen {
+		if !ok {
+			return nil, fmt.Errorf("missing chunk %d/%d", i+1, total)
+		}
+	}
+
+	var result []byte
+	var computedParity byte
+	for _, payload := range ordered {
+		result = append(result, payload...)
+	}
+	for _, b := range result {
+		computedParity ^= b
+	}
+	if computedParity != parity {
+		return nil, fmt.Errorf("reassembled content failed parity check (got %#x, want %#x)", computedParity, parity)
+	}
+
+	return result, nil
+}
+
+// encodeChunks renders every Structured Append member produced by
+// splitIntoChunks, in order, at the requested image size. All members share
+// one QR version, chosen by chunkSequenceVersion, so every rendered image
+// comes out the same size.
+func encodeChunks(content []byte, recLevel qrcode.RecoveryLevel, size int) ([]image.Image, error) {
+	chunks, err := splitIntoChunks(content, recLevel)
+	if err != nil {
+		return nil, err
+	}
+	version, err := chunkSequenceVersion(chunks, recLevel)
+	if err != nil {
+		return nil, err
+	}
+	images := make([]image.Image, 0, len(chunks))
+	for _, c := range chunks {
+		img, err := encodeChunk(c, recLevel, version, size)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// encodeChunkBitmaps renders every Structured Append member's module grid,
+// for sequential display by TerminalRenderer. All members share one QR
+// version, chosen by chunkSequenceVersion, so every grid comes out the
+// same size.
+func encodeChunkBitmaps(content []byte, recLevel qrcode.RecoveryLevel) ([][][]bool, error) {
+	chunks, err := splitIntoChunks(content, recLevel)
+	if err != nil {
+		return nil, err
+	}
+	version, err := chunkSequenceVersion(chunks, recLevel)
+	if err != nil {
+		return nil, err
+	}
+	bitmaps := make([][][]bool, 0, len(chunks))
+	for _, c := range chunks {
+		bitmap, err := chunkBitmap(c, recLevel, version)
+		if err != nil {
+			return nil, err
+		}
+		bitmaps = append(bitmaps, bitmap)
+	}
+	return bitmaps, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * file2qr - Convert files to QR codes
+ * Copyright (C) 2025 file2qr contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skip2/go-qrcode"
+)
+
+func TestStructuredAppendHeaderRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		index   int
+		total   int
+		parity  byte
+		payload []byte
+	}{
+		{"first of two", 0, 2, 0x00, []byte("hello")},
+		{"last of sixteen", 15, 16, 0xFF, []byte{0x01, 0x02}},
+		{"single chunk", 0, 1, 0x7F, []byte{0x80, 0x81, 0x82}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := qrChunk{index: tc.index, total: tc.total, parity: tc.parity, payload: tc.payload}
+			header := structuredAppendHeader(c)
+
+			data := append(append([]byte{}, header[:]...), tc.payload...)
+
+			parsed, ok := parseStructuredAppendHeader(data)
+			if !ok {
+				t.Fatalf("parseStructuredAppendHeader did not recognize a header it just built")
+			}
+			if parsed.index != tc.index {
+				t.Errorf("index = %d, want %d", parsed.index, tc.index)
+			}
+			if parsed.total != tc.total {
+				t.Errorf("total = %d, want %d", parsed.total, tc.total)
+			}
+			if parsed.parity != tc.parity {
+				t.Errorf("parity = %#x, want %#x", parsed.parity, tc.parity)
+			}
+			if !bytes.Equal(parsed.payload, tc.payload) {
+				t.Errorf("payload = %v, want %v", parsed.payload, tc.payload)
+			}
+		})
+	}
+}
+
+// TestParseStructuredAppendHeaderRejectsPlainPayload guards against the
+// false-positive collision a magic-less mode-nibble check used to allow:
+// a plain, non-chunked payload whose first byte happens to start with the
+// Structured Append mode nibble (0x3) must never be misparsed as a chunk.
+func TestParseStructuredAppendHeaderRejectsPlainPayload(t *testing.T) {
+	plain := []byte{0x30, 0x00, 0x00, 'h', 'i'}
+	if _, ok := parseStructuredAppendHeader(plain); ok {
+		t.Fatalf("parseStructuredAppendHeader accepted a plain payload lacking structuredAppendMagic")
+	}
+}
+
+func TestSplitAndReassembleChunksRoundTrip(t *testing.T) {
+	original := bytes.Repeat([]byte{0x00, 0x7F, 0x80, 0xFF, 0xA5}, 2000)
+
+	chunks, err := splitIntoChunks(original, qrcode.Medium)
+	if err != nil {
+		t.Fatalf("splitIntoChunks: %v", err)
+	}
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks for a %d-byte payload, got %d", len(original), len(chunks))
+	}
+
+	var parsed []parsedChunk
+	for _, c := range chunks {
+		header := structuredAppendHeader(c)
+		data := append(append([]byte{}, header[:]...), c.payload...)
+
+		pc, ok := parseStructuredAppendHeader(data)
+		if !ok {
+			t.Fatalf("parseStructuredAppendHeader did not recognize chunk %d/%d", c.index+1, c.total)
+		}
+		parsed = append(parsed, pc)
+	}
+
+	reassembled, err := reassembleChunks(parsed)
+	if err != nil {
+		t.Fatalf("reassembleChunks: %v", err)
+	}
+	if !bytes.Equal(reassembled, original) {
+		t.Fatalf("reassembled content does not match original (lengths %d vs %d)", len(reassembled), len(original))
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * file2qr - Convert files to QR codes
+ * Copyright (C) 2025 file2qr contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressMagic prefixes every compressed payload so the decode subcommand
+// can tell a compressed stream from a plain one and recover the algorithm
+// used, without the caller having to pass -z again on the way back out.
+var compressMagic = [4]byte{'F', '2', 'Q', '1'}
+
+// compressAlgo identifies the compressor used for a payload, stored as the
+// byte immediately following compressMagic.
+type compressAlgo byte
+
+const (
+	compressNone compressAlgo = iota
+	compressGzip
+	compressZstd
+)
+
+// parseCompressAlgo maps the -z/--compress flag value onto a compressAlgo.
+func parseCompressAlgo(name string) (compressAlgo, error) {
+	switch name {
+	case "", "none":
+		return compressNone, nil
+	case "gzip":
+		return compressGzip, nil
+	case "zstd":
+		return compressZstd, nil
+	default:
+		return compressNone, fmt.Errorf("unknown compression algorithm %q (want none, gzip, or zstd)", name)
+	}
+}
+
+// compressPayload compresses data with algo and prepends the magic header,
+// unless doing so would not actually shrink the payload (e.g. data that is
+// already compressed), in which case it returns data unchanged, printing a
+// warning when stderr is a terminal.
+func compressPayload(data []byte, algo compressAlgo) ([]byte, error) {
+	if algo == compressNone {
+		return data, nil
+	}
+
+	compressed, err := compressBytes(data, algo)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{}, compressMagic[:]...)
+	header = append(header, byte(algo))
+	out := append(header, compressed...)
+
+	if len(out) >= len(data) {
+		if isTerminal(os.Stderr.Fd()) {
+			fmt.Fprintf(os.Stderr, "Compression would increase size (%d -> %d bytes); skipping\n", len(data), len(out))
+		}
+		return data, nil
+	}
+
+	if isTerminal(os.Stderr.Fd()) {
+		ratio := float64(len(out)) / float64(len(data)) * 100
+		fmt.Fprintf(os.Stderr, "Compressed %d bytes to %d bytes (%.1f%% of original)\n", len(data), len(out), ratio)
+	}
+	return out, nil
+}
+
+// compressBytes runs data through the given algorithm's compressor.
+func compressBytes(data []byte, algo compressAlgo) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch algo {
+	case compressGzip:
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("gzip compressing: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("gzip compressing: %w", err)
+		}
+	case compressZstd:
+		w, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, fmt.Errorf("zstd compressing: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("zstd compressing: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("zstd compressing: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm %d", algo)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decompressPayload checks data for the compressMagic header and, if
+// present, decompresses it with the algorithm recorded there. Data without
+// the header is returned unchanged, so the decode subcommand works
+// equally well on payloads that were never compressed.
+func decompressPayload(data []byte) ([]byte, error) {
+	if len(data) < 5 || !bytes.Equal(data[:4], compressMagic[:]) {
+		return data, nil
+	}
+
+	algo := compressAlgo(data[4])
+	body := data[5:]
+
+	switch algo {
+	case compressGzip:
+		r, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("gzip decompressing: %w", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case compressZstd:
+		r, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("zstd decompressing: %w", err)
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	default:
+		return nil, fmt.Errorf("unknown compression algorithm byte %#x", algo)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * file2qr - Convert files to QR codes
+ * Copyright (C) 2025 file2qr contributors
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// chunkGutter is the blank margin, in pixels, left between cells when
+// tiling chunks into a grid PNG.
+const chunkGutter = 12
+
+// chunkLabelHeight is the vertical space reserved under each grid cell for
+// its "Chunk N/T" label.
+const chunkLabelHeight = 16
+
+// parseFormat validates the --format flag, applying the documented
+// defaults: png for a single QR code, gif for a chunked sequence.
+func parseFormat(name string, chunked bool) (string, error) {
+	if name == "" {
+		if chunked {
+			return "gif", nil
+		}
+		return "png", nil
+	}
+	switch name {
+	case "png", "gif", "grid":
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (want png, gif, or grid)", name)
+	}
+}
+
+// writeChunksAsGIF encodes every chunk image as one frame of an animated
+// GIF, looping forever so a phone camera can scan it off a repeating
+// display ("data-over-video" transfer).
+func writeChunksAsGIF(images []image.Image, outputFile string, frameDelay time.Duration) error {
+	g := &gif.GIF{}
+	delay := int(frameDelay / (10 * time.Millisecond)) // GIF delays are in 1/100s units
+	if delay <= 0 {
+		delay = 1
+	}
+
+	for _, img := range images {
+		palettedImg := image.NewPaletted(img.Bounds(), []color.Color{color.White, color.Black})
+		draw.Draw(palettedImg, img.Bounds(), img, image.Point{}, draw.Src)
+		g.Image = append(g.Image, palettedImg)
+		g.Delay = append(g.Delay, delay)
+		// Each chunk is a full, self-contained frame, not an incremental
+		// update over the last one — without DisposalBackground, a viewer
+		// is free to leave the previous frame's modules in place under any
+		// pixels the new frame doesn't touch, bleeding one chunk's QR into
+		// the next.
+		g.Disposal = append(g.Disposal, gif.DisposalBackground)
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", outputFile, err)
+	}
+	defer f.Close()
+
+	if err := gif.EncodeAll(f, g); err != nil {
+		return fmt.Errorf("encoding GIF: %w", err)
+	}
+	if isTerminal(os.Stderr.Fd()) {
+		fmt.Fprintf(os.Stderr, "Animated QR code (%d chunks) saved to: %s\n", len(images), outputFile)
+	}
+	return nil
+}
+
+// writeChunksAsGrid tiles every chunk into a single near-square grid PNG,
+// separated by chunkGutter and labeled with its position in the sequence.
+func writeChunksAsGrid(images []image.Image, outputFile string) error {
+	if len(images) == 0 {
+		return fmt.Errorf("no chunks to tile")
+	}
+
+	cols := int(math.Ceil(math.Sqrt(float64(len(images)))))
+	rows := (len(images) + cols - 1) / cols
+
+	cellW := images[0].Bounds().Dx()
+	cellH := images[0].Bounds().Dy()
+
+	gridW := cols*cellW + (cols+1)*chunkGutter
+	gridH := rows*(cellH+chunkLabelHeight) + (rows+1)*chunkGutter
+
+	grid := image.NewRGBA(image.Rect(0, 0, gridW, gridH))
+	draw.Draw(grid, grid.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for i, img := range images {
+		col := i % cols
+		row := i / cols
+		x := chunkGutter + col*(cellW+chunkGutter)
+		y := chunkGutter + row*(cellH+chunkLabelHeight+chunkGutter)
+
+		dstRect := image.Rect(x, y, x+cellW, y+cellH)
+		draw.Draw(grid, dstRect, img, image.Point{}, draw.Src)
+
+		label := fmt.Sprintf("Chunk %d/%d", i+1, len(images))
+		drawLabel(grid, label, x, y+cellH+chunkLabelHeight-4)
+	}
+
+	f, err := os.Create(outputFile)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", outputFile, err)
+	}
+	defer f.Close()
+
+	if err := png.Encode(f, grid); err != nil {
+		return fmt.Errorf("encoding grid PNG: %w", err)
+	}
+	if isTerminal(os.Stderr.Fd()) {
+		fmt.Fprintf(os.Stderr, "Grid of %d chunks saved to: %s\n", len(images), outputFile)
+	}
+	return nil
+}
+
+// drawLabel renders s in the basic bitmap font with its baseline at (x, y).
+func drawLabel(dst draw.Image, s string, x, y int) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.Black),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
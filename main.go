@@ -19,13 +19,17 @@
 package main
 
 import (
+	"bufio"
 	"encoding/base64"
 	"flag"
 	"fmt"
 	"image"
+	"image/png"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/skip2/go-qrcode"
 )
@@ -36,60 +40,75 @@ const (
 	ProgramName = "file2qr"
 )
 
-// displayImageInTerminal shows an image directly in the terminal
-func displayImageInTerminal(img image.Image) {
-	// Get image dimensions
-	bounds := img.Bounds()
+// writeChunkFiles saves each chunk image as name-01.png, name-02.png, ...
+// alongside the requested output path, preserving its extension.
+func writeChunkFiles(images []image.Image, outputFile string) error {
+	ext := filepath.Ext(outputFile)
+	base := strings.TrimSuffix(outputFile, ext)
+	if ext == "" {
+		ext = ".png"
+	}
 
-	// Ensure the bounds are non-empty
-	if bounds.Dx() <= 0 || bounds.Dy() <= 0 {
-		fmt.Fprintf(os.Stderr, "Error: Image has invalid dimensions\n")
-		return
+	for i, img := range images {
+		name := fmt.Sprintf("%s-%02d%s", base, i+1, ext)
+		f, err := os.Create(name)
+		if err != nil {
+			return fmt.Errorf("creating %s: %w", name, err)
+		}
+		err = png.Encode(f, img)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+		if isTerminal(os.Stderr.Fd()) {
+			fmt.Fprintf(os.Stderr, "QR code chunk saved to: %s\n", name)
+		}
 	}
+	return nil
+}
 
-	// Calculate padding to ensure the QR code is square in the terminal
-	// Since terminal characters are usually taller than wide, we need
-	// to add some horizontal spacing to make the QR code square
-	const horizontalPadding = "  " // Two spaces for horizontal padding
-
-	// Print top padding line to visually frame the QR code
-	fmt.Print("\n") // Extra line for visual separation
-
-	// Print the QR code using blocks
-	for y := bounds.Min.Y; y < bounds.Max.Y-1; y += 2 {
-		fmt.Print(horizontalPadding) // Start padding
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			// Get the colors of the top and bottom pixels in this column
-			top := img.At(x, y)
-			bottom := img.At(x, y+1)
-
-			// For QR codes, we only care if a pixel is set or not
-			// This simplifies the output and ensures consistent appearance
-			_, _, _, topAlpha := top.RGBA()
-			_, _, _, bottomAlpha := bottom.RGBA()
-
-			// QR codes have black/white pixels, so we can use simple block characters
-			// This creates a more scannable QR code in the terminal
-			if topAlpha > 0 && bottomAlpha > 0 {
-				// Both pixels are black
-				fmt.Print("█") // Full block
-			} else if topAlpha > 0 {
-				// Only top pixel is black
-				fmt.Print("▀") // Upper half block
-			} else if bottomAlpha > 0 {
-				// Only bottom pixel is black
-				fmt.Print("▄") // Lower half block
-			} else {
-				// Both pixels are white
-				fmt.Print(" ") // Space (empty)
-			}
+// displayChunksInTerminal renders each chunk in sequence, pausing between
+// them so the user can scan one before the next is drawn.
+func displayChunksInTerminal(bitmaps [][][]bool, renderer *TerminalRenderer) error {
+	reader := bufio.NewReader(os.Stdin)
+	for i, bitmap := range bitmaps {
+		fmt.Printf("Chunk %d/%d\n", i+1, len(bitmaps))
+		if err := renderer.Render(bitmap); err != nil {
+			return err
+		}
+
+		if i == len(bitmaps)-1 {
+			break
+		}
+		fmt.Print("Press ENTER for next chunk, or q to quit: ")
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if strings.TrimSpace(strings.ToLower(line)) == "q" {
+			break
 		}
-		fmt.Print(horizontalPadding) // End padding
-		fmt.Print("\n")
 	}
+	return nil
+}
+
+// qrCodeQuietZone is the fixed quiet-zone width, in modules, that
+// skip2/go-qrcode bakes into QRCode.Bitmap(). TerminalRenderer expects a
+// bare module grid and adds its own --term-quiet-zone border around it, so
+// that built-in margin must be trimmed off first or the rendered QR code
+// ends up with double the requested border (and --term-quiet-zone 0 would
+// still show one).
+const qrCodeQuietZone = 4
 
-	// Print bottom padding line to frame the QR code
-	fmt.Print("\n") // Extra line for visual separation
+// stripQuietZone removes border modules of width border from each edge of
+// bitmap, returning the bare module grid underneath.
+func stripQuietZone(bitmap [][]bool, border int) [][]bool {
+	size := len(bitmap) - 2*border
+	stripped := make([][]bool, size)
+	for y := 0; y < size; y++ {
+		stripped[y] = bitmap[y+border][border : border+size]
+	}
+	return stripped
 }
 
 // readFromStdin reads all data from standard input
@@ -104,6 +123,7 @@ func showUsage() {
 	flag.PrintDefaults()
 	fmt.Fprintf(os.Stderr, "\nIf FILE is not specified, %s reads from standard input.\n", ProgramName)
 	fmt.Fprintf(os.Stderr, "If -o/--output is not specified, displays QR code in terminal.\n")
+	fmt.Fprintf(os.Stderr, "\nUse '%s decode FILE...' to reconstruct a file from QR code images.\n", ProgramName)
 }
 
 // showVersion prints version information
@@ -116,12 +136,24 @@ func showVersion() {
 }
 
 func main() {
+	// "file2qr decode ..." reconstructs a file from QR images instead of
+	// encoding one; dispatch to it before setting up the encoder's flags.
+	if len(os.Args) > 1 && os.Args[1] == "decode" {
+		runDecode(os.Args[2:])
+		return
+	}
+
 	// Define command line flags
 	outputFile := flag.String("o", "", "Output QR code file path (PNG format)")
 	size := flag.Int("s", 256, "QR code size in pixels")
 	recovery := flag.String("r", "medium", "QR code recovery level: low, medium, high, highest")
-	terminalSize := flag.Int("t", 40, "Size of QR code when displayed in terminal")
 	base64Flag := flag.Bool("b", false, "Base64 encode content (recommended for binary files)")
+	compress := flag.String("z", "none", "Compress content before encoding: none, gzip, zstd")
+	format := flag.String("format", "", "Chunked output format: png, gif, grid (default: png for a single QR, gif when chunked)")
+	frameDelay := flag.Duration("frame-delay", 500*time.Millisecond, "Delay between frames in --format gif")
+	termMode := flag.String("term-mode", "half", "Terminal rendering mode: half, full, small, ascii")
+	termInvert := flag.Bool("term-invert", false, "Invert foreground/background when rendering in the terminal")
+	termQuietZone := flag.Int("term-quiet-zone", 4, "Quiet zone width in modules when rendering in the terminal")
 	versionFlag := flag.Bool("v", false, "Display version information and exit")
 	helpFlag := flag.Bool("h", false, "Display this help and exit")
 
@@ -129,8 +161,8 @@ func main() {
 	flag.StringVar(outputFile, "output", *outputFile, "Output QR code file path (PNG format)")
 	flag.IntVar(size, "size", *size, "QR code size in pixels")
 	flag.StringVar(recovery, "recovery", *recovery, "QR code recovery level: low, medium, high, highest")
-	flag.IntVar(terminalSize, "term-size", *terminalSize, "Size of QR code when displayed in terminal")
 	flag.BoolVar(base64Flag, "base64", *base64Flag, "Base64 encode content (recommended for binary files)")
+	flag.StringVar(compress, "compress", *compress, "Compress content before encoding: none, gzip, zstd")
 	flag.BoolVar(versionFlag, "version", *versionFlag, "Display version information and exit")
 	flag.BoolVar(helpFlag, "help", *helpFlag, "Display this help and exit")
 
@@ -173,15 +205,27 @@ func main() {
 		}
 	}
 
+	// Compress before base64/QR encoding if requested
+	compressAlgo, err := parseCompressAlgo(*compress)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	encodedData, err := compressPayload(inputData, compressAlgo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error compressing data: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Base64 encode if requested
 	var qrContent string
 	if *base64Flag {
-		qrContent = base64.StdEncoding.EncodeToString(inputData)
+		qrContent = base64.StdEncoding.EncodeToString(encodedData)
 		if isTerminal(os.Stderr.Fd()) {
 			fmt.Fprintf(os.Stderr, "Data encoded as Base64 (length: %d characters)\n", len(qrContent))
 		}
 	} else {
-		qrContent = string(inputData)
+		qrContent = string(encodedData)
 	}
 
 	// Determine recovery level
@@ -199,6 +243,68 @@ func main() {
 		recLevel = qrcode.Medium
 	}
 
+	// Split across multiple QR codes via Structured Append if the content
+	// doesn't fit in a single symbol at the chosen recovery level.
+	if needsChunking([]byte(qrContent), recLevel) {
+		chunkImages, err := encodeChunks([]byte(qrContent), recLevel, *size)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating chunked QR codes: %v\n", err)
+			os.Exit(1)
+		}
+		if isTerminal(os.Stderr.Fd()) {
+			fmt.Fprintf(os.Stderr, "Content size (%d bytes) exceeds single-QR capacity; split into %d chunks\n", len(qrContent), len(chunkImages))
+		}
+
+		chosenFormat, err := parseFormat(*format, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if *outputFile != "" {
+			switch chosenFormat {
+			case "gif":
+				err = writeChunksAsGIF(chunkImages, *outputFile, *frameDelay)
+			case "grid":
+				err = writeChunksAsGrid(chunkImages, *outputFile)
+			default:
+				err = writeChunkFiles(chunkImages, *outputFile)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error saving chunked QR codes: %v\n", err)
+				os.Exit(1)
+			}
+		} else if isTerminal(os.Stdout.Fd()) {
+			chunkBitmaps, err := encodeChunkBitmaps([]byte(qrContent), recLevel)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating chunked QR codes: %v\n", err)
+				os.Exit(1)
+			}
+			renderer, err := newTerminalRendererFromFlags(*termMode, *termInvert, *termQuietZone)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := displayChunksInTerminal(chunkBitmaps, renderer); err != nil {
+				fmt.Fprintf(os.Stderr, "Error displaying chunked QR codes: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: Output is not a terminal. Use -o/--output to specify an output file.\n")
+			os.Exit(1)
+		}
+
+		if isTerminal(os.Stderr.Fd()) {
+			if *base64Flag {
+				fmt.Fprintf(os.Stderr, "Original data size: %d bytes\n", len(inputData))
+				fmt.Fprintf(os.Stderr, "Base64 encoded size: %d characters\n", len(qrContent))
+			} else {
+				fmt.Fprintf(os.Stderr, "Data size: %d bytes\n", len(inputData))
+			}
+		}
+		return
+	}
+
 	// Generate QR code
 	qrImage, err := qrcode.New(qrContent, recLevel)
 	if err != nil {
@@ -224,7 +330,15 @@ func main() {
 	} else {
 		// Output to terminal
 		if isTerminal(os.Stdout.Fd()) {
-			displayImageInTerminal(qrImage.Image(*terminalSize))
+			renderer, err := newTerminalRendererFromFlags(*termMode, *termInvert, *termQuietZone)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := renderer.Render(stripQuietZone(qrImage.Bitmap(), qrCodeQuietZone)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error displaying QR code: %v\n", err)
+				os.Exit(1)
+			}
 		} else {
 			// If stdout is not a terminal, we can't display the image visually
 			fmt.Fprintf(os.Stderr, "Error: Output is not a terminal. Use -o/--output to specify an output file.\n")